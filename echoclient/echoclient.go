@@ -5,25 +5,210 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/evanj/gogrpccanceldemo/echopb"
+	"github.com/evanj/gogrpccanceldemo/reattach"
+	"github.com/evanj/gogrpccanceldemo/retry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// logCancelDiagnostics decodes a CancelDiagnostics detail off grpcErr, if the server attached one,
+// and logs it. This is the client side of the demo's worked example of carrying a structured
+// cancellation cause across the wire: the server attaches CancelDiagnostics via
+// status.WithDetails, and the client decodes it here instead of just printing Details=%#v.
+func logCancelDiagnostics(grpcErr *status.Status) {
+	for _, d := range grpcErr.Details() {
+		diag, ok := d.(*echopb.CancelDiagnostics)
+		if !ok {
+			continue
+		}
+		log.Printf("  CancelDiagnostics: observedBy=%q cause=%s remainingDeadline=%s requestId=%d",
+			diag.ObservedBy, diag.Cause, diag.RemainingDeadline.AsDuration(), diag.RequestId)
+	}
+}
+
+// runEchoStreamCancel opens an EchoStream, sends a few messages, then cancels the context partway
+// through, logging how many responses arrived before the stream reported the client's
+// cancellation and checking that the stream's final error is codes.Canceled. Note that canceling the client's own context tears the
+// stream down transport-side, so the server never gets to return a status of its own: the
+// logCancelDiagnostics call below is a no-op here (grpcErr.Details() is empty). See
+// runEchoStreamActionError for a streaming scenario where the server's CancelDiagnostics-bearing
+// status actually reaches the client.
+func runEchoStreamCancel(ctx context.Context, client echopb.EchoClient, input string) {
+	log.Printf("streaming request, client cancels mid-stream ...")
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.EchoStream(streamCtx)
+	if err != nil {
+		panic(err)
+	}
+
+	const numMessages = 5
+	for i := 0; i < numMessages; i++ {
+		req := &echopb.EchoRequest{
+			Input:       fmt.Sprintf("%s %d", input, i),
+			ServerSleep: durationpb.New(50 * time.Millisecond),
+		}
+		if err := stream.Send(req); err != nil {
+			panic(err)
+		}
+	}
+
+	received := 0
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			grpcErr, ok := status.FromError(err)
+			if !ok || grpcErr.Code() != codes.Canceled {
+				panic(fmt.Sprintf("expected canceled error; received=%d err=%s", received, err))
+			}
+			log.Printf("  received %d of %d responses before cancel; grpcErr.Code=%s Message=%#v",
+				received, numMessages, grpcErr.Code().String(), grpcErr.Message())
+			logCancelDiagnostics(grpcErr)
+			return
+		}
+		received++
+		log.Printf("  resp=%s", resp.String())
+		if received == 2 {
+			// cancel mid-stream: the server should keep delivering any responses it had already
+			// produced before it observes ctx.Done().
+			cancel()
+		}
+	}
+}
+
+// runEchoStreamActionError sends a few plain messages over an EchoStream, then a final message
+// with Action=RETURN_CONTEXT_DEADLINE_EXCEEDED to make the server return a deadline exceeded
+// status on an otherwise-live stream. Unlike runEchoStreamCancel, nothing here tears the stream
+// down from the client side, so the server's final status -- and its CancelDiagnostics detail --
+// actually reaches the client, giving a working worked example of the wire-carried diagnostics
+// this demo is meant to show for streaming RPCs.
+func runEchoStreamActionError(ctx context.Context, client echopb.EchoClient, input string) {
+	log.Printf("streaming request, last message triggers a server-side deadline exceeded ...")
+	stream, err := client.EchoStream(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	requests := []*echopb.EchoRequest{
+		{Input: fmt.Sprintf("%s 0", input)},
+		{Input: fmt.Sprintf("%s 1", input)},
+		{Input: fmt.Sprintf("%s 2", input), Action: echopb.ServerAction_RETURN_CONTEXT_DEADLINE_EXCEEDED},
+	}
+	for _, req := range requests {
+		if err := stream.Send(req); err != nil {
+			panic(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		panic(err)
+	}
+
+	received := 0
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			grpcErr, ok := status.FromError(err)
+			if !ok || grpcErr.Code() != codes.DeadlineExceeded {
+				panic(fmt.Sprintf("expected deadline exceeded error; received=%d err=%s", received, err))
+			}
+			log.Printf("  received %d of %d responses before server-side deadline exceeded; grpcErr.Code=%s Message=%#v",
+				received, len(requests)-1, grpcErr.Code().String(), grpcErr.Message())
+			logCancelDiagnostics(grpcErr)
+			return
+		}
+		received++
+		log.Printf("  resp=%s", resp.String())
+	}
+}
+
+// runHealthCheck implements the "healthcheck" subcommand: it invokes Check once, or streams
+// Watch updates if --watch is given, against the server's grpc.health.v1 Health service, using a
+// user-supplied deadline. This gives a worked example of how cancel/deadline behavior interacts
+// with the standard health protocol, including how Watch's server-streaming call reacts when the
+// deadline is hit or the client cancels.
+func runHealthCheck(args []string) {
+	fs := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8001", "server address")
+	service := fs.String("service", "echopb.Echo", "service name to check; empty string checks overall server health")
+	deadline := fs.Duration("deadline", 5*time.Second, "deadline for the health check RPC(s)")
+	watch := fs.Bool("watch", false, "stream health updates with Watch instead of a single Check")
+	if err := fs.Parse(args); err != nil {
+		panic(err)
+	}
+
+	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *deadline)
+	defer cancel()
+	req := &healthpb.HealthCheckRequest{Service: *service}
+
+	if !*watch {
+		resp, err := client.Check(ctx, req)
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("Check service=%q status=%s", *service, resp.Status)
+		return
+	}
+
+	stream, err := client.Watch(ctx, req)
+	if err != nil {
+		panic(err)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			grpcErr, ok := status.FromError(err)
+			if ok {
+				log.Printf("Watch stream ended: code=%s message=%#v", grpcErr.Code().String(), grpcErr.Message())
+			} else {
+				log.Printf("Watch stream ended: err=%s", err)
+			}
+			return
+		}
+		log.Printf("Watch service=%q status=%s", *service, resp.Status)
+	}
+}
+
 func main() {
-	addr := flag.String("addr", "localhost:8001", "server address")
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		runHealthCheck(os.Args[2:])
+		return
+	}
+
+	addr := flag.String("addr", "localhost:8001", "server address; ignored if "+reattach.EnvVar+" is set to valid JSON")
 	withBlock := flag.Bool("withBlock", true, "if we should use the WithBlock dial option")
 	dialTimeout := flag.Duration("dialTimeout", time.Minute, "timeout to use for DialContext")
 	input := flag.String("input", "example echo request input", "message to echo")
 	flag.Parse()
 
-	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	dialAddr := *addr
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	if info, err := reattach.FromEnv(); err == nil {
+		log.Printf("%s is set; reattaching to addr=%s instead of --addr", reattach.EnvVar, info.Addr)
+		dialAddr = info.Addr
+		unaryInterceptor, streamInterceptor := reattach.ClientInterceptors(info.Token)
+		dialOptions = append(dialOptions,
+			grpc.WithUnaryInterceptor(unaryInterceptor), grpc.WithStreamInterceptor(streamInterceptor))
+	}
 	if *withBlock {
 		log.Printf("setting Dial option WithBlock")
 		dialOptions = append(dialOptions, grpc.WithBlock())
@@ -35,8 +220,8 @@ func main() {
 		dialCtx, cancel = context.WithTimeout(dialCtx, *dialTimeout)
 		log.Printf("setting Dial timeout=%s", dialTimeout.String())
 	}
-	log.Printf("connecting to addr=%s ...", *addr)
-	conn, err := grpc.DialContext(dialCtx, *addr, dialOptions...)
+	log.Printf("connecting to addr=%s ...", dialAddr)
+	conn, err := grpc.DialContext(dialCtx, dialAddr, dialOptions...)
 	cancel()
 	if err != nil {
 		panic(err)
@@ -44,10 +229,22 @@ func main() {
 	defer conn.Close()
 	client := echopb.NewEchoClient(conn)
 
-	// normal request
-	log.Printf("plain request ...")
+	// normal request, wrapped in the default retry policy: this call succeeds on the first
+	// attempt, so the policy is a no-op here, but it demonstrates how to wrap client.Echo so that
+	// transient transport errors (Unavailable, ResourceExhausted, a DeadlineExceeded with budget
+	// remaining) are retried with backoff instead of surfaced to the caller.
+	log.Printf("plain request (wrapped in retry.Do) ...")
 	ctx := context.Background()
-	resp, err := client.Echo(ctx, &echopb.EchoRequest{Input: *input})
+	policy := retry.DefaultPolicy()
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		log.Printf("  retry attempt=%d after err=%s; sleeping %s", attempt, err, delay.String())
+	}
+	var resp *echopb.EchoResponse
+	err = retry.Do(ctx, policy, func(ctx context.Context) error {
+		var err error
+		resp, err = client.Echo(ctx, &echopb.EchoRequest{Input: *input})
+		return err
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -64,8 +261,8 @@ func main() {
 	resp, err = client.Echo(ctxTimeout, req)
 	cancel()
 	if grpcErr, ok := status.FromError(err); ok && grpcErr.Code() == codes.DeadlineExceeded {
-		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v Details=%#v",
-			resp, grpcErr.Code().String(), grpcErr.Message(), grpcErr.Details())
+		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v", resp, grpcErr.Code().String(), grpcErr.Message())
+		logCancelDiagnostics(grpcErr)
 	} else {
 		panic(fmt.Sprintf("expected deadline exceeded error; resp=%#v err=%s", resp, err))
 	}
@@ -78,8 +275,8 @@ func main() {
 	}
 	resp, err = client.Echo(ctx, req)
 	if grpcErr, ok := status.FromError(err); ok && grpcErr.Code() == codes.DeadlineExceeded {
-		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v Details=%#v",
-			resp, grpcErr.Code().String(), grpcErr.Message(), grpcErr.Details())
+		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v", resp, grpcErr.Code().String(), grpcErr.Message())
+		logCancelDiagnostics(grpcErr)
 	} else {
 		panic(fmt.Sprintf("expected deadline exceeded error; resp=%#v err=%s", resp, err))
 	}
@@ -92,12 +289,27 @@ func main() {
 	}
 	resp, err = client.Echo(ctx, req)
 	if grpcErr, ok := status.FromError(err); ok && grpcErr.Code() == codes.Canceled {
-		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v Details=%#v",
-			resp, grpcErr.Code().String(), grpcErr.Message(), grpcErr.Details())
+		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v", resp, grpcErr.Code().String(), grpcErr.Message())
+		logCancelDiagnostics(grpcErr)
 	} else {
 		panic(fmt.Sprintf("expected canceled error; resp=%#v err=%s", resp, err))
 	}
 
+	// server returns the first error (deadline exceeded) instead of the last (canceled); compare
+	// with RETURN_CONTEXT_CANCELED above, which hits the same race but keeps the buggy behavior
+	log.Printf("server returns first error instead of last (firsterr.Group fix) ...")
+	req = &echopb.EchoRequest{
+		Input:  *input,
+		Action: echopb.ServerAction_RETURN_FIRST_ERROR,
+	}
+	resp, err = client.Echo(ctx, req)
+	if grpcErr, ok := status.FromError(err); ok && grpcErr.Code() == codes.DeadlineExceeded {
+		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v", resp, grpcErr.Code().String(), grpcErr.Message())
+		logCancelDiagnostics(grpcErr)
+	} else {
+		panic(fmt.Sprintf("expected deadline exceeded error; resp=%#v err=%s", resp, err))
+	}
+
 	// client cancels the request after a short wait, sending a cancel to the server
 	log.Printf("client cancels request ...")
 	req = &echopb.EchoRequest{
@@ -119,8 +331,8 @@ func main() {
 	wg.Wait()
 
 	if grpcErr, ok := status.FromError(err); ok && grpcErr.Code() == codes.Canceled {
-		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v Details=%#v",
-			resp, grpcErr.Code().String(), grpcErr.Message(), grpcErr.Details())
+		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v", resp, grpcErr.Code().String(), grpcErr.Message())
+		logCancelDiagnostics(grpcErr)
 	} else {
 		panic(fmt.Sprintf("expected canceled error; resp=%#v err=%s", resp, err))
 	}
@@ -135,8 +347,8 @@ func main() {
 	}
 	resp, err = client.Echo(ctxWithCancel, req)
 	if grpcErr, ok := status.FromError(err); ok && grpcErr.Code() == codes.Canceled {
-		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v Details=%#v",
-			resp, grpcErr.Code().String(), grpcErr.Message(), grpcErr.Details())
+		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v", resp, grpcErr.Code().String(), grpcErr.Message())
+		logCancelDiagnostics(grpcErr)
 	} else {
 		panic(fmt.Sprintf("expected canceled error; resp=%#v err=%s", resp, err))
 	}
@@ -151,12 +363,18 @@ func main() {
 	}
 	resp, err = client.Echo(ctxTimeout, req)
 	if grpcErr, ok := status.FromError(err); ok && grpcErr.Code() == codes.DeadlineExceeded {
-		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v Details=%#v",
-			resp, grpcErr.Code().String(), grpcErr.Message(), grpcErr.Details())
+		log.Printf("  resp=%#v grpcErr.Code=%s Message=%#v", resp, grpcErr.Code().String(), grpcErr.Message())
+		logCancelDiagnostics(grpcErr)
 	} else {
 		panic(fmt.Sprintf("expected deadline exceeded error; resp=%#v err=%s", resp, err))
 	}
 
+	// streaming request: client cancels mid-stream
+	runEchoStreamCancel(ctx, client, *input)
+
+	// streaming request: server-side deadline exceeded on an otherwise-live stream
+	runEchoStreamActionError(ctx, client, *input)
+
 	err = conn.Close()
 	if err != nil {
 		panic(err)
@@ -27,6 +27,7 @@ const (
 	ServerAction_UNSPECIFIED                      ServerAction = 0
 	ServerAction_RETURN_CONTEXT_DEADLINE_EXCEEDED ServerAction = 1
 	ServerAction_RETURN_CONTEXT_CANCELED          ServerAction = 2
+	ServerAction_RETURN_FIRST_ERROR               ServerAction = 3
 )
 
 // Enum value maps for ServerAction.
@@ -35,11 +36,13 @@ var (
 		0: "UNSPECIFIED",
 		1: "RETURN_CONTEXT_DEADLINE_EXCEEDED",
 		2: "RETURN_CONTEXT_CANCELED",
+		3: "RETURN_FIRST_ERROR",
 	}
 	ServerAction_value = map[string]int32{
 		"UNSPECIFIED":                      0,
 		"RETURN_CONTEXT_DEADLINE_EXCEEDED": 1,
 		"RETURN_CONTEXT_CANCELED":          2,
+		"RETURN_FIRST_ERROR":               3,
 	}
 )
 
@@ -70,6 +73,55 @@ func (ServerAction) EnumDescriptor() ([]byte, []int) {
 	return file_proto_echo_proto_rawDescGZIP(), []int{0}
 }
 
+type CancelCause int32
+
+const (
+	CancelCause_CANCEL_CAUSE_UNSPECIFIED       CancelCause = 0
+	CancelCause_CANCEL_CAUSE_CANCELED          CancelCause = 1
+	CancelCause_CANCEL_CAUSE_DEADLINE_EXCEEDED CancelCause = 2
+)
+
+// Enum value maps for CancelCause.
+var (
+	CancelCause_name = map[int32]string{
+		0: "CANCEL_CAUSE_UNSPECIFIED",
+		1: "CANCEL_CAUSE_CANCELED",
+		2: "CANCEL_CAUSE_DEADLINE_EXCEEDED",
+	}
+	CancelCause_value = map[string]int32{
+		"CANCEL_CAUSE_UNSPECIFIED":       0,
+		"CANCEL_CAUSE_CANCELED":          1,
+		"CANCEL_CAUSE_DEADLINE_EXCEEDED": 2,
+	}
+)
+
+func (x CancelCause) Enum() *CancelCause {
+	p := new(CancelCause)
+	*p = x
+	return p
+}
+
+func (x CancelCause) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CancelCause) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_echo_proto_enumTypes[1].Descriptor()
+}
+
+func (CancelCause) Type() protoreflect.EnumType {
+	return &file_proto_echo_proto_enumTypes[1]
+}
+
+func (x CancelCause) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CancelCause.Descriptor instead.
+func (CancelCause) EnumDescriptor() ([]byte, []int) {
+	return file_proto_echo_proto_rawDescGZIP(), []int{1}
+}
+
 type EchoRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -180,6 +232,79 @@ func (x *EchoResponse) GetOutput() string {
 	return ""
 }
 
+// CancelDiagnostics carries forensic detail about an observed cancellation or deadline, attached
+// to the returned Status via status.WithDetails.
+type CancelDiagnostics struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ObservedBy        string               `protobuf:"bytes,1,opt,name=observed_by,json=observedBy,proto3" json:"observed_by,omitempty"`
+	Cause             CancelCause          `protobuf:"varint,2,opt,name=cause,proto3,enum=echopb.CancelCause" json:"cause,omitempty"`
+	RemainingDeadline *durationpb.Duration `protobuf:"bytes,3,opt,name=remaining_deadline,json=remainingDeadline,proto3" json:"remaining_deadline,omitempty"`
+	RequestId         int64                `protobuf:"varint,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+}
+
+func (x *CancelDiagnostics) Reset() {
+	*x = CancelDiagnostics{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_echo_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelDiagnostics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelDiagnostics) ProtoMessage() {}
+
+func (x *CancelDiagnostics) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_echo_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelDiagnostics.ProtoReflect.Descriptor instead.
+func (*CancelDiagnostics) Descriptor() ([]byte, []int) {
+	return file_proto_echo_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CancelDiagnostics) GetObservedBy() string {
+	if x != nil {
+		return x.ObservedBy
+	}
+	return ""
+}
+
+func (x *CancelDiagnostics) GetCause() CancelCause {
+	if x != nil {
+		return x.Cause
+	}
+	return CancelCause_CANCEL_CAUSE_UNSPECIFIED
+}
+
+func (x *CancelDiagnostics) GetRemainingDeadline() *durationpb.Duration {
+	if x != nil {
+		return x.RemainingDeadline
+	}
+	return nil
+}
+
+func (x *CancelDiagnostics) GetRequestId() int64 {
+	if x != nil {
+		return x.RequestId
+	}
+	return 0
+}
+
 var File_proto_echo_proto protoreflect.FileDescriptor
 
 var file_proto_echo_proto_rawDesc = []byte{
@@ -198,17 +323,42 @@ var file_proto_echo_proto_rawDesc = []byte{
 	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x26, 0x0a, 0x0c,
 	0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06,
 	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6f, 0x75,
-	0x74, 0x70, 0x75, 0x74, 0x2a, 0x62, 0x0a, 0x0c, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x41, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0f, 0x0a, 0x0b, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46,
-	0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x24, 0x0a, 0x20, 0x52, 0x45, 0x54, 0x55, 0x52, 0x4e, 0x5f,
-	0x43, 0x4f, 0x4e, 0x54, 0x45, 0x58, 0x54, 0x5f, 0x44, 0x45, 0x41, 0x44, 0x4c, 0x49, 0x4e, 0x45,
-	0x5f, 0x45, 0x58, 0x43, 0x45, 0x45, 0x44, 0x45, 0x44, 0x10, 0x01, 0x12, 0x1b, 0x0a, 0x17, 0x52,
-	0x45, 0x54, 0x55, 0x52, 0x4e, 0x5f, 0x43, 0x4f, 0x4e, 0x54, 0x45, 0x58, 0x54, 0x5f, 0x43, 0x41,
-	0x4e, 0x43, 0x45, 0x4c, 0x45, 0x44, 0x10, 0x02, 0x32, 0x3b, 0x0a, 0x04, 0x45, 0x63, 0x68, 0x6f,
-	0x12, 0x33, 0x0a, 0x04, 0x45, 0x63, 0x68, 0x6f, 0x12, 0x13, 0x2e, 0x65, 0x63, 0x68, 0x6f, 0x70,
-	0x62, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e,
-	0x65, 0x63, 0x68, 0x6f, 0x70, 0x62, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x22, 0x00, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x70, 0x75, 0x74, 0x22, 0xc8, 0x01, 0x0a, 0x11, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x44,
+	0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x62,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x64, 0x5f, 0x62, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x6f, 0x62, 0x73, 0x65, 0x72, 0x76, 0x65, 0x64, 0x42, 0x79, 0x12, 0x29, 0x0a, 0x05, 0x63,
+	0x61, 0x75, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x65, 0x63, 0x68,
+	0x6f, 0x70, 0x62, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x43, 0x61, 0x75, 0x73, 0x65, 0x52,
+	0x05, 0x63, 0x61, 0x75, 0x73, 0x65, 0x12, 0x48, 0x0a, 0x12, 0x72, 0x65, 0x6d, 0x61, 0x69, 0x6e,
+	0x69, 0x6e, 0x67, 0x5f, 0x64, 0x65, 0x61, 0x64, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x11, 0x72,
+	0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x44, 0x65, 0x61, 0x64, 0x6c, 0x69, 0x6e, 0x65,
+	0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x2a,
+	0x7a, 0x0a, 0x0c, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x0f, 0x0a, 0x0b, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00,
+	0x12, 0x24, 0x0a, 0x20, 0x52, 0x45, 0x54, 0x55, 0x52, 0x4e, 0x5f, 0x43, 0x4f, 0x4e, 0x54, 0x45,
+	0x58, 0x54, 0x5f, 0x44, 0x45, 0x41, 0x44, 0x4c, 0x49, 0x4e, 0x45, 0x5f, 0x45, 0x58, 0x43, 0x45,
+	0x45, 0x44, 0x45, 0x44, 0x10, 0x01, 0x12, 0x1b, 0x0a, 0x17, 0x52, 0x45, 0x54, 0x55, 0x52, 0x4e,
+	0x5f, 0x43, 0x4f, 0x4e, 0x54, 0x45, 0x58, 0x54, 0x5f, 0x43, 0x41, 0x4e, 0x43, 0x45, 0x4c, 0x45,
+	0x44, 0x10, 0x02, 0x12, 0x16, 0x0a, 0x12, 0x52, 0x45, 0x54, 0x55, 0x52, 0x4e, 0x5f, 0x46, 0x49,
+	0x52, 0x53, 0x54, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x03, 0x2a, 0x6a, 0x0a, 0x0b, 0x43,
+	0x61, 0x6e, 0x63, 0x65, 0x6c, 0x43, 0x61, 0x75, 0x73, 0x65, 0x12, 0x1c, 0x0a, 0x18, 0x43, 0x41,
+	0x4e, 0x43, 0x45, 0x4c, 0x5f, 0x43, 0x41, 0x55, 0x53, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45,
+	0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x19, 0x0a, 0x15, 0x43, 0x41, 0x4e, 0x43,
+	0x45, 0x4c, 0x5f, 0x43, 0x41, 0x55, 0x53, 0x45, 0x5f, 0x43, 0x41, 0x4e, 0x43, 0x45, 0x4c, 0x45,
+	0x44, 0x10, 0x01, 0x12, 0x22, 0x0a, 0x1e, 0x43, 0x41, 0x4e, 0x43, 0x45, 0x4c, 0x5f, 0x43, 0x41,
+	0x55, 0x53, 0x45, 0x5f, 0x44, 0x45, 0x41, 0x44, 0x4c, 0x49, 0x4e, 0x45, 0x5f, 0x45, 0x58, 0x43,
+	0x45, 0x45, 0x44, 0x45, 0x44, 0x10, 0x02, 0x32, 0x7a, 0x0a, 0x04, 0x45, 0x63, 0x68, 0x6f, 0x12,
+	0x33, 0x0a, 0x04, 0x45, 0x63, 0x68, 0x6f, 0x12, 0x13, 0x2e, 0x65, 0x63, 0x68, 0x6f, 0x70, 0x62,
+	0x2e, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x65,
+	0x63, 0x68, 0x6f, 0x70, 0x62, 0x2e, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x3d, 0x0a, 0x0a, 0x45, 0x63, 0x68, 0x6f, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x12, 0x13, 0x2e, 0x65, 0x63, 0x68, 0x6f, 0x70, 0x62, 0x2e, 0x45, 0x63, 0x68, 0x6f,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x65, 0x63, 0x68, 0x6f, 0x70, 0x62,
+	0x2e, 0x45, 0x63, 0x68, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28,
+	0x01, 0x30, 0x01, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -223,24 +373,30 @@ func file_proto_echo_proto_rawDescGZIP() []byte {
 	return file_proto_echo_proto_rawDescData
 }
 
-var file_proto_echo_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_echo_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_echo_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_echo_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_proto_echo_proto_goTypes = []interface{}{
 	(ServerAction)(0),           // 0: echopb.ServerAction
-	(*EchoRequest)(nil),         // 1: echopb.EchoRequest
-	(*EchoResponse)(nil),        // 2: echopb.EchoResponse
-	(*durationpb.Duration)(nil), // 3: google.protobuf.Duration
+	(CancelCause)(0),            // 1: echopb.CancelCause
+	(*EchoRequest)(nil),         // 2: echopb.EchoRequest
+	(*EchoResponse)(nil),        // 3: echopb.EchoResponse
+	(*CancelDiagnostics)(nil),   // 4: echopb.CancelDiagnostics
+	(*durationpb.Duration)(nil), // 5: google.protobuf.Duration
 }
 var file_proto_echo_proto_depIdxs = []int32{
-	3, // 0: echopb.EchoRequest.server_sleep:type_name -> google.protobuf.Duration
+	5, // 0: echopb.EchoRequest.server_sleep:type_name -> google.protobuf.Duration
 	0, // 1: echopb.EchoRequest.action:type_name -> echopb.ServerAction
-	1, // 2: echopb.Echo.Echo:input_type -> echopb.EchoRequest
-	2, // 3: echopb.Echo.Echo:output_type -> echopb.EchoResponse
-	3, // [3:4] is the sub-list for method output_type
-	2, // [2:3] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	1, // 2: echopb.CancelDiagnostics.cause:type_name -> echopb.CancelCause
+	5, // 3: echopb.CancelDiagnostics.remaining_deadline:type_name -> google.protobuf.Duration
+	2, // 4: echopb.Echo.Echo:input_type -> echopb.EchoRequest
+	2, // 5: echopb.Echo.EchoStream:input_type -> echopb.EchoRequest
+	3, // 6: echopb.Echo.Echo:output_type -> echopb.EchoResponse
+	3, // 7: echopb.Echo.EchoStream:output_type -> echopb.EchoResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_proto_echo_proto_init() }
@@ -273,14 +429,26 @@ func file_proto_echo_proto_init() {
 				return nil
 			}
 		}
+		file_proto_echo_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CancelDiagnostics); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_echo_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   2,
+			NumEnums:      2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -293,4 +461,4 @@ func file_proto_echo_proto_init() {
 	file_proto_echo_proto_rawDesc = nil
 	file_proto_echo_proto_goTypes = nil
 	file_proto_echo_proto_depIdxs = nil
-}
\ No newline at end of file
+}
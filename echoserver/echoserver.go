@@ -2,18 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net"
+	"os"
+	"os/signal"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/evanj/gogrpccanceldemo/echopb"
+	"github.com/evanj/gogrpccanceldemo/firsterr"
+	"github.com/evanj/gogrpccanceldemo/reattach"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// echoServiceName is the name the Health service reports status under; it matches the fully
+// qualified gRPC service name so health clients can check it with grpc_health_v1.
+const echoServiceName = "echopb.Echo"
+
 type server struct {
 	echopb.UnimplementedEchoServer
 	responseSleep time.Duration
@@ -24,12 +40,14 @@ func newServer(responseSleep time.Duration) *server {
 	return &server{echopb.UnimplementedEchoServer{}, responseSleep, atomic.Int64{}}
 }
 
-// simTwoTasks simulates running two tasks in parallel that respect cancelation. One task times out,
-// which cases the other to be canceled. The parent task's error handling returns the last error
-// instead of the first, so it return context.Canceled instead of context.DeadlineExceeded.
-// This is arguably a bug: the code should return the deadline exceeded error since that happened
-// first. However, there are many ways to write code that does something like this.
-func simTwoTasks(ctx context.Context) error {
+// simTwoTasksLastErrorWins simulates running two tasks in parallel that respect cancelation. One
+// task times out, which causes the other to be canceled. The parent task's error handling
+// returns the last error instead of the first, so it returns context.Canceled instead of
+// context.DeadlineExceeded. This is arguably a bug: the code should return the deadline exceeded
+// error since that happened first. However, there are many ways to write code that does
+// something like this. Kept for comparison with simTwoTasks, which fixes the bug with
+// firsterr.Group; see ServerAction_RETURN_CONTEXT_CANCELED and ServerAction_RETURN_FIRST_ERROR.
+func simTwoTasksLastErrorWins(ctx context.Context) error {
 	collectErrs := make(chan error)
 
 	// create a context that gets cancelled if an error occurs in either task
@@ -69,6 +87,76 @@ func simTwoTasks(ctx context.Context) error {
 	return lastErr
 }
 
+// simTwoTasks runs the same two sub-tasks as simTwoTasksLastErrorWins, but uses firsterr.Group so
+// the first error recorded wins: the sub-task that times out reports context.DeadlineExceeded,
+// and the sibling it cancels as a result reports context.Canceled, which firsterr.Group discards
+// in favor of the real root cause.
+func simTwoTasks(ctx context.Context) error {
+	g, _ := firsterr.WithContext(ctx)
+
+	g.Go(func(ctx context.Context) error {
+		// task one: times out
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+		err := ctx.Err()
+		log.Printf("  sub task one returning err=%T %s", err, err)
+		return err
+	})
+
+	g.Go(func(ctx context.Context) error {
+		// task two: simulates a longer task that respects cancelation (e.g. a gRPC call)
+		<-ctx.Done()
+		err := ctx.Err()
+		log.Printf("  sub task two returning err=%T %s", err, err)
+		return err
+	})
+
+	err := g.Wait()
+	log.Printf("  parent task returning err=%T %s", err, err)
+	return err
+}
+
+// cancelCauseFor maps a context error to the corresponding echopb.CancelCause, so
+// CancelDiagnostics carries a structured cause instead of requiring the client to pattern-match
+// the Status message.
+func cancelCauseFor(err error) echopb.CancelCause {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return echopb.CancelCause_CANCEL_CAUSE_DEADLINE_EXCEEDED
+	case errors.Is(err, context.Canceled):
+		return echopb.CancelCause_CANCEL_CAUSE_CANCELED
+	default:
+		return echopb.CancelCause_CANCEL_CAUSE_UNSPECIFIED
+	}
+}
+
+// statusWithCancelDiagnostics converts a context error observed on ctx into its equivalent gRPC
+// status and attaches a CancelDiagnostics detail recording observedBy (the code path that
+// observed it), the cause, the deadline remaining at the time it was observed, and the request
+// ID. This gives a client forensic detail about a cancellation that would otherwise only exist in
+// the server's logs.
+func statusWithCancelDiagnostics(ctx context.Context, requestID int64, observedBy string, err error) error {
+	// RemainingDeadline is left nil when ctx has no deadline at all, so a client can tell that
+	// apart from a deadline that expired with exactly 0ns to spare.
+	var remaining *durationpb.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining = durationpb.New(time.Until(deadline))
+	}
+	diag := &echopb.CancelDiagnostics{
+		ObservedBy:        observedBy,
+		Cause:             cancelCauseFor(err),
+		RemainingDeadline: remaining,
+		RequestId:         requestID,
+	}
+
+	st := status.FromContextError(err)
+	if stWithDetails, detailsErr := st.WithDetails(diag); detailsErr == nil {
+		return stWithDetails.Err()
+	}
+	return st.Err()
+}
+
 func (s *server) Echo(ctx context.Context, request *echopb.EchoRequest) (*echopb.EchoResponse, error) {
 	requestID := s.requestID.Add(1)
 
@@ -80,27 +168,52 @@ func (s *server) Echo(ctx context.Context, request *echopb.EchoRequest) (*echopb
 	log.Printf("reqID=%d starting with deadline? %t %s duration = %s",
 		requestID, ok, deadline.Format(time.RFC3339Nano), deadlineDuration.String())
 
+	return s.handleEchoMessage(ctx, requestID, request)
+}
+
+// handleEchoMessage implements the shared per-message logic for both the unary Echo RPC and
+// EchoStream: it applies the request's Action, sleeps for the requested duration, and echoes the
+// input. It is factored out so that EchoStream can apply the same cancellation semantics to each
+// message it receives.
+func (s *server) handleEchoMessage(
+	ctx context.Context, requestID int64, request *echopb.EchoRequest,
+) (*echopb.EchoResponse, error) {
 	// handle a special action
 	switch request.Action {
 	case echopb.ServerAction_UNSPECIFIED:
 		// no special action: normal handling below
 
 	case echopb.ServerAction_RETURN_CONTEXT_DEADLINE_EXCEEDED:
-		ctx, cancel := context.WithTimeout(ctx, 10*time.Nanosecond)
-		<-ctx.Done()
+		deadlineCtx, cancel := context.WithTimeout(ctx, 10*time.Nanosecond)
+		<-deadlineCtx.Done()
 		cancel()
-		err := ctx.Err()
+		err := deadlineCtx.Err()
 		log.Printf("reqID=%d Action=%s; returning err=%T %s; == context.DeadlineExceeded? %t",
 			requestID, request.Action.String(), err, err, err == context.DeadlineExceeded)
-		return nil, err
+		return nil, statusWithCancelDiagnostics(deadlineCtx, requestID, "handleEchoMessage", err)
 
 	case echopb.ServerAction_RETURN_CONTEXT_CANCELED:
 		log.Printf("reqID=%d Action=%s; spawning two sub-tasks ...",
 			requestID, request.Action.String())
-		err := simTwoTasks(ctx)
+		err := simTwoTasksLastErrorWins(ctx)
 		log.Printf("reqID=%d Action=%s; returning err=%T %s; == context.Canceled? %t",
 			requestID, request.Action, err, err, err == context.Canceled)
-		return nil, err
+		return nil, statusWithCancelDiagnostics(ctx, requestID, "simTwoTasksLastErrorWins", err)
+
+	case echopb.ServerAction_RETURN_FIRST_ERROR:
+		log.Printf("reqID=%d Action=%s; spawning two sub-tasks ...",
+			requestID, request.Action.String())
+		err := simTwoTasks(ctx)
+		log.Printf("reqID=%d Action=%s; returning err=%T %s; == context.DeadlineExceeded? %t",
+			requestID, request.Action, err, err, err == context.DeadlineExceeded)
+		// firsterr.Group prefers the non-Canceled error, so a DeadlineExceeded here really was
+		// observed by the sub-task that timed out; anything else (including Canceled) only tells
+		// us the group's own cancel fired, not which sub-task noticed first.
+		observedBy := "simTwoTasks"
+		if errors.Is(err, context.DeadlineExceeded) {
+			observedBy = "simTwoTasks: sub task that timed out"
+		}
+		return nil, statusWithCancelDiagnostics(ctx, requestID, observedBy, err)
 
 	default:
 		log.Printf("reqID=%d Unknown Action=%s %d; returning gRPC error",
@@ -123,20 +236,106 @@ func (s *server) Echo(ctx context.Context, request *echopb.EchoRequest) (*echopb
 	return resp, nil
 }
 
+// echoStreamErr wraps an error that EchoStream encountered while handling requestID: if the
+// stream's context is done, it returns the equivalent status with CancelDiagnostics attached;
+// otherwise it returns err unchanged. Note this only reaches the client when the stream is still
+// live when the status is returned (e.g. a server-observed deadline, or a ServerAction's own
+// nested context firing) -- when the client itself cancels its context, gRPC tears the stream
+// down transport-side before the server's in-flight Send/return can deliver a trailer, so no
+// status (and no CancelDiagnostics) reaches the client in that case; see runEchoStreamCancel.
+func (s *server) echoStreamErr(ctx context.Context, requestID int64, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		grpcErr := statusWithCancelDiagnostics(ctx, requestID, "EchoStream", ctxErr)
+		log.Printf("reqID=%d EchoStream observed ctx.Err()=%s; returning %s", requestID, ctxErr, grpcErr)
+		return grpcErr
+	}
+	return err
+}
+
+// EchoStream echoes each request message as it arrives, applying its ServerSleep/Action exactly
+// like the unary Echo RPC. This demonstrates cancellation in a bidirectional stream: if the
+// client cancels or the deadline is hit mid-stream, stream.Context().Done() fires and Recv/Send
+// return the gRPC status derived from ctx.Err() via status.FromContextError, while any responses
+// already produced before that point are still delivered.
+func (s *server) EchoStream(stream echopb.Echo_EchoStreamServer) error {
+	ctx := stream.Context()
+
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			log.Printf("EchoStream: client half-closed the stream")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		requestID := s.requestID.Add(1)
+		resp, err := s.handleEchoMessage(ctx, requestID, request)
+		if err != nil {
+			return s.echoStreamErr(ctx, requestID, err)
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return s.echoStreamErr(ctx, requestID, err)
+		}
+	}
+}
+
 func main() {
 	addr := flag.String("addr", "localhost:8001", "listening address")
 	responseSleep := flag.Duration("responseSleep", 0, "time to sleep before responding")
+	reattachMode := flag.Bool("reattach", false,
+		"listen on an ephemeral loopback port and publish connection info via "+reattach.EnvVar+
+			" instead of binding --addr, so a debugger can attach before the client connects")
 	flag.Parse()
 
-	lis, err := net.Listen("tcp", *addr)
-	if err != nil {
-		panic(err)
+	var lis net.Listener
+	serverOpts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+	if *reattachMode {
+		var err error
+		lis, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			panic(err)
+		}
+		token, err := reattach.NewToken()
+		if err != nil {
+			panic(err)
+		}
+		info := reattach.Info{Addr: lis.Addr().String(), Network: "tcp", Token: token}
+		fmt.Print(reattach.Publish(info))
+
+		unaryInterceptor, streamInterceptor := reattach.ServerInterceptors(token)
+		serverOpts = append(serverOpts,
+			grpc.UnaryInterceptor(unaryInterceptor), grpc.StreamInterceptor(streamInterceptor))
+	} else {
+		var err error
+		lis, err = net.Listen("tcp", *addr)
+		if err != nil {
+			panic(err)
+		}
 	}
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(serverOpts...)
 	echopb.RegisterEchoServer(s, newServer(*responseSleep))
 
-	log.Printf("serving on %s ...", *addr)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(echoServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+
+	// On SIGINT/SIGTERM, report NOT_SERVING so in-flight Watch streams and new Check calls see the
+	// shutdown before GracefulStop drains existing RPCs and closes the listener. This lets the demo
+	// show how cancel/deadline behavior interacts with the health protocol during a shutdown.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.Printf("received signal %s; reporting NOT_SERVING and starting graceful shutdown ...", sig)
+		healthServer.SetServingStatus(echoServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+		s.GracefulStop()
+	}()
+
+	log.Printf("serving on %s ...", lis.Addr().String())
 	if err := s.Serve(lis); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/evanj/gogrpccanceldemo/echopb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSimTwoTasksLastErrorWins_ReturnsCanceled(t *testing.T) {
+	// The buggy path is kept for pedagogical comparison with simTwoTasks: it returns the last
+	// error collected, which is always the sibling's context.Canceled, not the deadline exceeded
+	// error that actually caused it.
+	err := simTwoTasksLastErrorWins(context.Background())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("simTwoTasksLastErrorWins() = %v; want context.Canceled", err)
+	}
+}
+
+func TestSimTwoTasks_ReturnsDeadlineExceeded(t *testing.T) {
+	err := simTwoTasks(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("simTwoTasks() = %v; want context.DeadlineExceeded", err)
+	}
+}
+
+func TestHandleEchoMessage_ReturnFirstError(t *testing.T) {
+	s := newServer(0)
+	_, err := s.handleEchoMessage(context.Background(), 1, &echopb.EchoRequest{
+		Action: echopb.ServerAction_RETURN_FIRST_ERROR,
+	})
+	// handleEchoMessage converts the context error into a gRPC status with CancelDiagnostics
+	// attached, so this checks the status code rather than errors.Is against context.DeadlineExceeded.
+	grpcErr, ok := status.FromError(err)
+	if !ok || grpcErr.Code() != codes.DeadlineExceeded {
+		t.Fatalf("handleEchoMessage(RETURN_FIRST_ERROR) = %v; want codes.DeadlineExceeded", err)
+	}
+
+	details := grpcErr.Details()
+	if len(details) != 1 {
+		t.Fatalf("len(grpcErr.Details()) = %d; want 1", len(details))
+	}
+	diag, ok := details[0].(*echopb.CancelDiagnostics)
+	if !ok {
+		t.Fatalf("details[0] = %T; want *echopb.CancelDiagnostics", details[0])
+	}
+	if diag.Cause != echopb.CancelCause_CANCEL_CAUSE_DEADLINE_EXCEEDED {
+		t.Errorf("diag.Cause = %s; want CANCEL_CAUSE_DEADLINE_EXCEEDED", diag.Cause)
+	}
+	if diag.RequestId != 1 {
+		t.Errorf("diag.RequestId = %d; want 1", diag.RequestId)
+	}
+}
@@ -0,0 +1,71 @@
+// Package firsterr runs a group of goroutines that share a context, canceling the group on the
+// first error and returning that first error rather than whichever error happens to be collected
+// last.
+package firsterr
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Group runs a set of functions concurrently, all sharing a context derived from the one passed
+// to WithContext. The derived context is canceled as soon as any function returns a non-nil
+// error, so the remaining functions can observe ctx.Done() and stop early.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// WithContext returns a new Group and an associated Context derived from ctx. The derived
+// Context is canceled the first time a function passed to Go returns a non-nil error, or when
+// Wait returns, whichever occurs first.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go starts f in a new goroutine, passing it the Group's context. If f returns an error, the
+// Group's context is canceled so other running functions can stop early.
+func (g *Group) Go(f func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(g.ctx); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			g.cancel()
+		}
+	}()
+}
+
+// Wait blocks until all functions started with Go have returned, then returns the first "real"
+// error recorded, if any.
+//
+// Which error is recorded first is a data race in general: canceling the context to stop a
+// sibling early often causes that sibling to itself return context.Canceled, and whether the
+// true root cause or that resulting context.Canceled reaches Wait's bookkeeping first depends on
+// goroutine scheduling. So Wait prefers the first recorded error that is not context.Canceled;
+// only if every recorded error is context.Canceled (e.g. the parent context passed to
+// WithContext was itself canceled) does it fall back to returning the first one recorded.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, err := range g.errs {
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	if len(g.errs) > 0 {
+		return g.errs[0]
+	}
+	return nil
+}
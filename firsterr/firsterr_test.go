@@ -0,0 +1,78 @@
+package firsterr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroup_PreservesRootCauseOverSiblingCancellation(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+
+	g.Go(func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := g.Wait()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() = %v; want context.DeadlineExceeded", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Group's derived context should be canceled after Wait")
+	}
+}
+
+func TestGroup_NoErrorsReturnsNil(t *testing.T) {
+	g, _ := WithContext(context.Background())
+	g.Go(func(ctx context.Context) error { return nil })
+	g.Go(func(ctx context.Context) error { return nil })
+
+	if err := g.Wait(); err != nil {
+		t.Errorf("Wait() = %v; want nil", err)
+	}
+}
+
+func TestGroup_AllCanceledFallsBackToCanceled(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	cancelParent()
+
+	g, ctx := WithContext(parent)
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := g.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() = %v; want context.Canceled", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Group's derived context should be canceled after Wait")
+	}
+}
+
+func TestGroup_CancelsSiblingsOnFirstError(t *testing.T) {
+	g, _ := WithContext(context.Background())
+	wantErr := errors.New("boom")
+
+	g.Go(func(ctx context.Context) error { return wantErr })
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := g.Wait(); !errors.Is(err, wantErr) {
+		t.Errorf("Wait() = %v; want %v", err, wantErr)
+	}
+}
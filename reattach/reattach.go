@@ -0,0 +1,111 @@
+// Package reattach implements an "unmanaged server" mode modeled on Terraform's
+// TF_REATTACH_PROVIDERS: the server listens on an ephemeral port under a debugger and publishes
+// its address plus a one-time token as JSON, so a client launched separately can reattach to it
+// without racing the server process's own startup/shutdown.
+package reattach
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// EnvVar is the environment variable the server publishes its Info to and the client reads it
+// from.
+const EnvVar = "ECHO_REATTACH"
+
+// tokenMetadataKey carries Info.Token on every RPC so the server can verify the client actually
+// read the published Info, rather than e.g. guessing a well-known reattach port.
+const tokenMetadataKey = "echo-reattach-token"
+
+// Info is published by the server via EnvVar as JSON, and read back by the client.
+type Info struct {
+	Addr    string `json:"addr"`
+	Network string `json:"network"`
+	Token   string `json:"token"`
+}
+
+// NewToken returns a random one-time token suitable for Info.Token.
+func NewToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("reattach: generating token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Publish formats info as the JSON blob the client expects and returns instructions for setting
+// EnvVar, for the caller to print, e.g. to a server's stdout.
+func Publish(info Info) string {
+	data, err := json.Marshal(info)
+	if err != nil {
+		// Info only contains strings: Marshal cannot fail.
+		panic(err)
+	}
+	return fmt.Sprintf("attach a debugger to this process, then in the client's environment:\n  export %s=%s\n",
+		EnvVar, data)
+}
+
+// FromEnv reads and parses Info from EnvVar. It returns an error if EnvVar is unset or invalid,
+// which callers use to fall back to their normal (non-reattach) connection logic.
+func FromEnv() (Info, error) {
+	raw := os.Getenv(EnvVar)
+	if raw == "" {
+		return Info{}, fmt.Errorf("reattach: %s is not set", EnvVar)
+	}
+	var info Info
+	if err := json.Unmarshal([]byte(raw), &info); err != nil {
+		return Info{}, fmt.Errorf("reattach: parsing %s: %w", EnvVar, err)
+	}
+	return info, nil
+}
+
+// ServerInterceptors returns interceptors that reject any RPC that does not carry token in
+// tokenMetadataKey, for installation on the grpc.Server created in reattach mode.
+func ServerInterceptors(token string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkToken(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkToken(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
+
+func checkToken(ctx context.Context, token string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(tokenMetadataKey)) != 1 || md.Get(tokenMetadataKey)[0] != token {
+		return status.Error(codes.Unauthenticated, "reattach: missing or invalid token")
+	}
+	return nil
+}
+
+// ClientInterceptors returns interceptors that attach token to every outgoing RPC in
+// tokenMetadataKey, for installation on the grpc.ClientConn dialed from Info.
+func ClientInterceptors(token string) (grpc.UnaryClientInterceptor, grpc.StreamClientInterceptor) {
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, tokenMetadataKey, token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = metadata.AppendToOutgoingContext(ctx, tokenMetadataKey, token)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	return unary, stream
+}
@@ -0,0 +1,142 @@
+// Package retry implements the call-level retry policy used by the echoclient demo: exponential
+// backoff with jitter, modeled on gRPC's connection-backoff spec
+// (https://github.com/grpc/grpc/blob/master/doc/connection-backoff.md), applied around a single
+// RPC attempt rather than a connection attempt.
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy configures the backoff and retry behavior of Do.
+type Policy struct {
+	// BaseDelay is the delay before the first retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff delay.
+	MaxDelay time.Duration
+	// Multiplier is applied to the delay after each retry.
+	Multiplier float64
+	// Jitter randomizes each computed delay within +/- Jitter as a fraction, e.g. 0.2 means the
+	// actual delay is chosen uniformly from [delay*0.8, delay*1.2].
+	Jitter float64
+	// MaxAttempts bounds the total number of calls to fn, including the first attempt. Zero means
+	// no limit: Do retries until fn succeeds, ctx is done, or a non-retryable error is returned.
+	MaxAttempts int
+	// RetryableCodes lists the gRPC codes that trigger a retry. A codes.DeadlineExceeded error is
+	// only retried if ctx still has time remaining, since otherwise the parent deadline has
+	// already been exceeded and retrying cannot succeed.
+	RetryableCodes []codes.Code
+	// OnRetry, if set, is called after each failed attempt that will be retried, before sleeping.
+	// attempt is 1 for the first retry (i.e. the second overall attempt).
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultPolicy returns the backoff parameters from gRPC's connection-backoff spec, retrying the
+// transport and load-shedding errors that are safe to retry blindly.
+func DefaultPolicy() Policy {
+	return Policy{
+		BaseDelay:  1 * time.Second,
+		MaxDelay:   120 * time.Second,
+		Multiplier: 1.6,
+		Jitter:     0.2,
+		RetryableCodes: []codes.Code{
+			codes.Unavailable,
+			codes.DeadlineExceeded,
+			codes.ResourceExhausted,
+		},
+	}
+}
+
+// delay computes the backoff delay before retry number retries+1 (so delay(0) is BaseDelay, used
+// before the second attempt), before jitter is applied.
+func (p Policy) delay(retries int) time.Duration {
+	d := float64(p.BaseDelay) * math.Pow(p.Multiplier, float64(retries))
+	if max := float64(p.MaxDelay); d > max {
+		d = max
+	}
+	return time.Duration(d)
+}
+
+// jittered randomizes d uniformly within [d*(1-p.Jitter), d*(1+p.Jitter)].
+func (p Policy) jittered(d time.Duration, rnd *rand.Rand) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	lo := float64(d) * (1 - p.Jitter)
+	spread := float64(d) * 2 * p.Jitter
+	return time.Duration(lo + rnd.Float64()*spread)
+}
+
+// retryable reports whether err should trigger another attempt under ctx.
+func (p Policy) retryable(ctx context.Context, err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	retryableCode := false
+	for _, c := range p.RetryableCodes {
+		if st.Code() == c {
+			retryableCode = true
+			break
+		}
+	}
+	if !retryableCode {
+		return false
+	}
+	if st.Code() == codes.DeadlineExceeded {
+		deadline, ok := ctx.Deadline()
+		if !ok || !time.Now().Before(deadline) {
+			return false
+		}
+	}
+	return true
+}
+
+// Do calls fn, retrying with exponential backoff and jitter according to p when fn returns an
+// error whose gRPC code is in p.RetryableCodes. It checks ctx.Err() before every attempt,
+// including the first, so a context that is already canceled or expired short-circuits
+// immediately without calling fn. Do returns the error from the most recent attempt.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	var lastErr error
+	for attempt := 0; p.MaxAttempts == 0 || attempt < p.MaxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+
+		lastErr = fn(ctx)
+		if lastErr == nil {
+			return nil
+		}
+		if !p.retryable(ctx, lastErr) {
+			return lastErr
+		}
+		if p.MaxAttempts > 0 && attempt+1 >= p.MaxAttempts {
+			return lastErr
+		}
+
+		d := p.jittered(p.delay(attempt), rnd)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt+1, lastErr, d)
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return lastErr
+		case <-timer.C:
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/evanj/gogrpccanceldemo/echopb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// flakyEchoServer fails the first failures calls to Echo with codes.Unavailable, then succeeds.
+type flakyEchoServer struct {
+	echopb.UnimplementedEchoServer
+	failures int32
+	calls    atomic.Int32
+}
+
+func (s *flakyEchoServer) Echo(ctx context.Context, req *echopb.EchoRequest) (*echopb.EchoResponse, error) {
+	call := s.calls.Add(1)
+	if call <= s.failures {
+		return nil, status.Error(codes.Unavailable, "flaking on purpose")
+	}
+	return &echopb.EchoResponse{Output: "echoed: " + req.Input}, nil
+}
+
+// dialFlakyServer starts an in-process gRPC server wrapping srv and returns a client connected to
+// it over bufconn, along with a cleanup func.
+func dialFlakyServer(t *testing.T, srv *flakyEchoServer) (echopb.EchoClient, func()) {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	echopb.RegisterEchoServer(s, srv)
+	go func() {
+		if err := s.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			t.Logf("server exited: %s", err)
+		}
+	}()
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	conn, err := grpc.DialContext(context.Background(), "bufconn",
+		grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.DialContext: %s", err)
+	}
+
+	return echopb.NewEchoClient(conn), func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+func TestDo_RetriesUntilSuccess(t *testing.T) {
+	srv := &flakyEchoServer{failures: 2}
+	client, cleanup := dialFlakyServer(t, srv)
+	defer cleanup()
+
+	policy := DefaultPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 10 * time.Millisecond
+
+	var retries int
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) { retries++ }
+
+	var resp *echopb.EchoResponse
+	err := Do(context.Background(), policy, func(ctx context.Context) error {
+		var err error
+		resp, err = client.Echo(ctx, &echopb.EchoRequest{Input: "hello"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Do returned err=%s; want nil after the server stops flaking", err)
+	}
+	if resp.Output != "echoed: hello" {
+		t.Errorf("resp.Output=%q; want %q", resp.Output, "echoed: hello")
+	}
+	if retries != 2 {
+		t.Errorf("retries=%d; want 2", retries)
+	}
+}
+
+func TestDo_MaxAttemptsGivesUp(t *testing.T) {
+	srv := &flakyEchoServer{failures: 10}
+	client, cleanup := dialFlakyServer(t, srv)
+	defer cleanup()
+
+	policy := DefaultPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 10 * time.Millisecond
+	policy.MaxAttempts = 3
+
+	err := Do(context.Background(), policy, func(ctx context.Context) error {
+		_, err := client.Echo(ctx, &echopb.EchoRequest{Input: "hello"})
+		return err
+	})
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unavailable {
+		t.Fatalf("err=%v; want a codes.Unavailable status", err)
+	}
+	if got := srv.calls.Load(); got != int32(policy.MaxAttempts) {
+		t.Errorf("server saw %d calls; want MaxAttempts=%d", got, policy.MaxAttempts)
+	}
+}
+
+func TestDo_NonRetryableErrorStopsImmediately(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), DefaultPolicy(), func(ctx context.Context) error {
+		attempts++
+		return status.Error(codes.InvalidArgument, "bad request")
+	})
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("err=%v; want a codes.InvalidArgument status", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts=%d; want 1 (no retries for a non-retryable code)", attempts)
+	}
+}
+
+func TestDo_AlreadyCanceledContextShortCircuits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := Do(ctx, DefaultPolicy(), func(ctx context.Context) error {
+		attempts++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err=%v; want context.Canceled", err)
+	}
+	if attempts != 0 {
+		t.Errorf("attempts=%d; want 0 (fn should not be called with an already-canceled context)", attempts)
+	}
+}